@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yalefresne/streamlog/internal/capture"
+)
+
+// resolveInterface turns the -i flag's value into a concrete interface
+// name. If query is empty it prints a numbered table of every interface
+// capture.FindInterfaces finds and prompts for a selection on stdin;
+// otherwise it matches query against each interface's name or any of its
+// assigned IP addresses, mirroring the common pattern in gopacket examples.
+func resolveInterface(query string) (string, error) {
+	ifaces, err := capture.FindInterfaces()
+	if err != nil {
+		return "", fmt.Errorf("listing interfaces: %w", err)
+	}
+
+	if query == "" {
+		return promptForInterface(ifaces)
+	}
+
+	for _, iface := range ifaces {
+		if iface.Name == query {
+			return iface.Name, nil
+		}
+		for _, addr := range iface.Addresses {
+			if addr == query {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface matches %q", query)
+}
+
+// printInterfaceTable prints every interface's index, name, description,
+// and addresses, one row per interface.
+func printInterfaceTable(ifaces []capture.Interface) {
+	for i, iface := range ifaces {
+		fmt.Printf("%2d) %-16s %-30s %s\n", i+1, iface.Name, iface.Description, strings.Join(iface.Addresses, ", "))
+	}
+}
+
+// promptForInterface prints the interface table and reads a 1-based
+// selection from stdin, retrying on invalid input.
+func promptForInterface(ifaces []capture.Interface) (string, error) {
+	if len(ifaces) == 0 {
+		return "", fmt.Errorf("no interfaces found")
+	}
+
+	printInterfaceTable(ifaces)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf("Select an interface [1-%d]: ", len(ifaces))
+		if !scanner.Scan() {
+			return "", fmt.Errorf("no selection made: %w", scanner.Err())
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || choice < 1 || choice > len(ifaces) {
+			fmt.Println("Invalid selection, try again.")
+			continue
+		}
+		return ifaces[choice-1].Name, nil
+	}
+}
@@ -1,182 +1,333 @@
-// streamlog captures DNS and TLS Client Hello (SNI) packets on enp1s0f0
-// using AF_PACKET to avoid libpcap dependency.
+// streamlog captures DNS and TLS Client Hello (SNI) packets, either live
+// off an AF_PACKET interface or from an offline pcap/pcapng file.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"syscall"
 
-	"github.com/gopacket/gopacket"
-	"github.com/gopacket/gopacket/afpacket"
 	"github.com/gopacket/gopacket/layers"
+
+	"github.com/yalefresne/streamlog/internal/capture"
+	"github.com/yalefresne/streamlog/internal/reassembly"
 )
 
 const (
-	device    = "enp1s0f0"
+	defaultBPFFilter = "udp port 53 or tcp port 443"
+
 	frameSize = 2048
 	numBlocks = 128
+
+	// jobQueueSize bounds how many raw frames can be queued per worker
+	// before the capture loop blocks on submit, trading latency for a
+	// fixed memory ceiling under bursty traffic.
+	jobQueueSize = 1024
 )
 
+// certDir is the directory unique certificate DERs get dumped to
+// (<sha256>.crt); empty disables dumping. Set once from the -certdir flag
+// in main before any worker starts.
+var certDir string
+
 func main() {
-	pageSize := os.Getpagesize()
-	blockSize := pageSize * numBlocks
+	iface := flag.String("i", "", "interface to capture on, by name or assigned IP (live mode; prompts if omitted)")
+	list := flag.Bool("list", false, "print the available interfaces and exit")
+	pcapFile := flag.String("r", "", "read packets from a .pcap/.pcapng file instead of a live interface")
+	bpfFilter := flag.String("f", defaultBPFFilter, "BPF filter expression")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of packet-decoding workers")
+	certDirFlag := flag.String("certdir", "", "directory to dump unique certificate DERs to as <sha256>.crt (disabled if empty)")
+	flag.Parse()
+	certDir = *certDirFlag
+
 	// Ensure we are running on Linux as AF_PACKET is Linux-specific.
-	if os.Getenv("GOOS") != "linux" && os.Getenv("GOOS") != "" {
+	if *pcapFile == "" && os.Getenv("GOOS") != "linux" && os.Getenv("GOOS") != "" {
 		log.Println("Warning: This program is designed for Linux (AF_PACKET).")
 	}
 
-	log.Printf("Starting capture on %s...", device)
+	if *list {
+		ifaces, err := capture.FindInterfaces()
+		if err != nil {
+			log.Fatalf("Error listing interfaces: %v", err)
+		}
+		printInterfaceTable(ifaces)
+		return
+	}
+
+	resolvedIface := *iface
+	if *pcapFile == "" {
+		var err error
+		resolvedIface, err = resolveInterface(*iface)
+		if err != nil {
+			log.Fatalf("Error resolving interface: %v", err)
+		}
+	}
 
-	// Create the AF_PACKET handle.
-	handle, err := afpacket.NewTPacket(
-		afpacket.OptInterface(device),
-		afpacket.OptFrameSize(frameSize),
-		afpacket.OptBlockSize(blockSize),
-		afpacket.OptNumBlocks(numBlocks), // Larger buffer for capture
-	)
+	source, err := openSource(*pcapFile, resolvedIface)
 	if err != nil {
-		log.Fatalf("Error creating AF_PACKET handle: %v", err)
+		log.Fatalf("Error opening capture source: %v", err)
 	}
-	defer handle.Close()
+	defer source.Close()
 
-	// Parse packets using gopacket.
-	packetSource := gopacket.NewPacketSource(handle, layers.LayerTypeEthernet)
+	if *bpfFilter != "" {
+		if err := source.SetBPF(*bpfFilter); err != nil {
+			log.Fatalf("Error setting BPF filter %q: %v", *bpfFilter, err)
+		}
+	}
 
 	// Channel to signal stop.
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
-	// Use a channel to process packets concurrently if needed, but since
-	// packet extraction is fast, we can process in the main loop or spawn
-	// goroutines per packet type.
-	// The requirement is "concurrently extract and print". We'll use a worker pool model
-	// or simple per-packet goroutine (might be too heavy). Let's use a fan-out approach.
-
-	packetChan := packetSource.Packets()
+	pool := newWorkerPool(*workers, jobQueueSize)
+	pool.start()
+	defer pool.stop()
 
 	log.Println("Listening for DNS (53) and TLS (443) packets...")
 
-	for {
-		select {
-		case <-stop:
-			log.Println("Stopping capture...")
-			return
-		case packet, ok := <-packetChan:
-			if !ok {
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			// ZeroCopyReadPacketData avoids allocating a gopacket.Packet
+			// per frame; its buffer is only valid until the next call, so
+			// every frame is copied before it crosses the worker channel.
+			data, ci, err := source.ZeroCopyReadPacketData()
+			if err != nil {
+				errs <- err
 				return
 			}
-			// Dispatch processing to a goroutine to handle "concurrently".
-			// Note: For high throughput, a worker pool is better, but for this
-			// assignment, spawning a goroutine per packet of interest simplifies logic.
-			go processPacket(packet)
+			frame := make([]byte, len(data))
+			copy(frame, data)
+			pool.submit(frame, ci.Timestamp)
+		}
+	}()
+
+	select {
+	case <-stop:
+		log.Println("Stopping capture...")
+	case err := <-errs:
+		// io.EOF is how an offline pcap/pcapng source reports "no more
+		// packets", not a failure; either way we fall through to the
+		// deferred source.Close()/pool.stop() so anything still buffered
+		// in the worker pool or the reassembler gets a chance to flush
+		// before the process exits.
+		if errors.Is(err, io.EOF) {
+			log.Println("Reached end of capture file.")
+		} else {
+			log.Printf("Error reading packet: %v", err)
 		}
 	}
 }
 
-func processPacket(packet gopacket.Packet) {
-	// We are looking for DNS (UDP/TCP 53) and TLS (TCP 443).
-	// Let's filter first.
-
-	// Check for DNS
-	if dnsLayer := packet.Layer(layers.LayerTypeDNS); dnsLayer != nil {
-		dns, _ := dnsLayer.(*layers.DNS)
-		if dns.QR {
-			// Response, maybe we want queries? "extract and print DNS queries"
-			// Usually queries are QR=false. But user said "DNS queries".
-			// Assuming they want to see what is being queried.
+// openSource picks the offline pcap reader when pcapFile is set, otherwise
+// opens iface as a live AF_PACKET ring buffer.
+func openSource(pcapFile, iface string) (capture.Source, error) {
+	if pcapFile != "" {
+		log.Printf("Reading packets from %s...", pcapFile)
+		return capture.OpenOfflineFile(pcapFile)
+	}
+
+	log.Printf("Starting capture on %s...", iface)
+	pageSize := os.Getpagesize()
+	return capture.OpenLive(iface, frameSize, pageSize*numBlocks, numBlocks)
+}
+
+// processDNS prints every query name carried by a DNS query message.
+// Responses (QR set) are skipped; we only care about what was looked up.
+func processDNS(dns *layers.DNS) {
+	if dns.QR {
+		return
+	}
+	for _, q := range dns.Questions {
+		fmt.Printf("[DNS] Query: %s (Type: %s)\n", string(q.Name), q.Type)
+	}
+}
+
+// sniFlowKey identifies one connection by its full 4-tuple, not just the
+// server endpoint, so a server ip:port serving more than one hostname at
+// once (CDNs, shared hosting) doesn't have its connections' SNIs cross
+// attributed to each other.
+type sniFlowKey struct {
+	clientIP   string
+	clientPort uint16
+	serverIP   string
+	serverPort uint16
+}
+
+// sniByFlow remembers the SNI seen on a Client Hello until the matching
+// Server Hello's Certificate message is logged, so the two can be
+// correlated despite arriving as separate reassembled streams. Entries are
+// removed once looked up, and capped at reassembly.DefaultMaxStreams
+// entries the same way internal/reassembly bounds its stream table, so a
+// connection that never completes its handshake can't grow this map
+// without limit.
+var sniByFlow = struct {
+	mu      sync.Mutex
+	entries map[sniFlowKey]string
+}{entries: make(map[sniFlowKey]string)}
+
+func rememberSNI(clientIP string, clientPort uint16, serverIP string, serverPort uint16, sni string) {
+	if sni == "" {
+		return
+	}
+	key := sniFlowKey{clientIP, clientPort, serverIP, serverPort}
+
+	sniByFlow.mu.Lock()
+	defer sniByFlow.mu.Unlock()
+	if _, exists := sniByFlow.entries[key]; !exists && len(sniByFlow.entries) >= reassembly.DefaultMaxStreams {
+		return // at capacity; drop rather than grow unboundedly
+	}
+	sniByFlow.entries[key] = sni
+}
+
+func lookupSNI(clientIP string, clientPort uint16, serverIP string, serverPort uint16) string {
+	key := sniFlowKey{clientIP, clientPort, serverIP, serverPort}
+
+	sniByFlow.mu.Lock()
+	defer sniByFlow.mu.Unlock()
+	sni, ok := sniByFlow.entries[key]
+	if !ok {
+		return ""
+	}
+	delete(sniByFlow.entries, key) // the Certificate only needs to be tagged once
+	return sni
+}
+
+// processTLS inspects a TCP payload for a Client Hello or Server Hello
+// record and, if found, prints its SNI/JA3 or JA3S fingerprint, and (for a
+// Server Hello followed by a Certificate message) every certificate in the
+// chain.
+func processTLS(srcIP, dstIP string, srcPort, dstPort uint16, payload []byte) {
+	if len(payload) <= 5 || payload[0] != 22 { // not a Handshake record
+		return
+	}
+
+	// Skip record header (5 bytes); handshake header is Type (1 byte) + Length (3 bytes).
+	switch payload[5] {
+	case 1: // Client Hello
+		sni := extractSNI(payload)
+		ch, ok := parseClientHello(payload)
+		if sni == "" && !ok {
 			return
 		}
-		if len(dns.Questions) > 0 {
-			for _, q := range dns.Questions {
-				fmt.Printf("[DNS] Query: %s (Type: %s)\n", string(q.Name), q.Type)
-			}
+		fp := capture.TLSFingerprint{
+			SNI:     sni,
+			SrcIP:   srcIP,
+			DstIP:   dstIP,
+			SrcPort: srcPort,
+			DstPort: dstPort,
+		}
+		if ok {
+			ch.SNI = sni
+			fp.JA3, fp.JA3Hash = capture.JA3(ch)
+		}
+		rememberSNI(srcIP, srcPort, dstIP, dstPort, sni)
+		fmt.Printf("[TLS] ClientHello SNI: %s JA3: %s (Src: %s, Dst: %s)\n", fp.SNI, fp.JA3Hash, fp.SrcIP, fp.DstIP)
+	case 2: // Server Hello
+		sh, ok := parseServerHello(payload)
+		if !ok {
+			return
+		}
+		fp := capture.TLSFingerprint{
+			SrcIP:   srcIP,
+			DstIP:   dstIP,
+			SrcPort: srcPort,
+			DstPort: dstPort,
 		}
+		fp.JA3S, fp.JA3SHash = capture.JA3S(sh)
+		fmt.Printf("[TLS] ServerHello JA3S: %s (Src: %s, Dst: %s)\n", fp.JA3SHash, fp.SrcIP, fp.DstIP)
+
+		processCertificates(payload, srcIP, dstIP, srcPort, dstPort)
+	}
+}
+
+// processCertificates looks for a Certificate handshake message in the
+// same buffer as the Server Hello, prints a summary of every certificate
+// it carries, and (if -certdir is set) dumps each one's DER to disk keyed
+// by its SHA-256 fingerprint.
+func processCertificates(payload []byte, srcIP, dstIP string, srcPort, dstPort uint16) {
+	certs, err := capture.ExtractCertificates(payload)
+	if err != nil || len(certs) == 0 {
 		return
 	}
 
-	// Check for TLS (TCP 443 usually, but let's check port layer)
-	if tcpLayer := packet.Layer(layers.LayerTypeTCP); tcpLayer != nil {
-		// Check destination port for Client Hello (target port 443)
-		// Or source port if capturing server response (Server Hello).
-		// User said "TLS Client Hello (SNI)". This is client -> server.
-		// So DstPort should be 443 is common, but could be others.
-		// We'll trust the payload parsing.
-
-		// Note: gopacket layers.TLS might not be fully reliable for all TLS traffic
-		// or if not enabled by default decoding.
-		// We can check if existing payload looks like TLS.
-
-		// If application layer is available
-		appLayer := packet.ApplicationLayer()
-		if appLayer != nil {
-			payload := appLayer.Payload()
-			// Basic TLS Client Hello check:
-			// Content Type: Handshake (22)
-			// Version: 0x0301 (TLS 1.0) or 0x0303 (TLS 1.2) etc.
-			// Handshake Type: Client Hello (1)
-
-			if len(payload) > 5 && payload[0] == 22 { // Handshake
-				// Skip record header (5 bytes)
-				// Handshake header: Type (1 byte), Length (3 bytes)
-				if payload[5] == 1 { // Client Hello
-					sni := extractSNI(payload)
-					if sni != "" {
-						fmt.Printf("[TLS] SNI: %s (Src: %s, Dst: %s)\n", sni, packet.NetworkLayer().NetworkFlow().Src(), packet.NetworkLayer().NetworkFlow().Dst())
-					}
-				}
-			}
-		}
+	sni := lookupSNI(dstIP, dstPort, srcIP, srcPort)
+	for _, cert := range certs {
+		fmt.Printf("[CERT] SNI: %s Subject: %s Issuer: %s SANs: %v SHA256: %s Serial: %s NotAfter: %s (Src: %s, Dst: %s)\n",
+			sni, cert.Subject, cert.Issuer, cert.SANs, cert.SHA256, cert.SerialNumber, cert.NotAfter, srcIP, dstIP)
+		dumpCertificate(cert)
 	}
 }
 
-// extractSNI parses the TLS Client Hello payload to find the SNI extension.
+// dumpCertificate writes cert's DER to <certDir>/<sha256>.crt if dumping
+// is enabled and that fingerprint hasn't already been written.
+func dumpCertificate(cert capture.Certificate) {
+	if certDir == "" {
+		return
+	}
+	path := filepath.Join(certDir, cert.SHA256+".crt")
+	if _, err := os.Stat(path); err == nil {
+		return // already dumped this certificate
+	}
+	if err := os.WriteFile(path, cert.DER, 0o644); err != nil {
+		log.Printf("Error writing certificate %s: %v", path, err)
+	}
+}
+
+// extractSNI parses the TLS Client Hello payload to find the SNI
+// extension. The parsing itself lives in capture.ExtractSNI so the
+// router package (which needs the same logic to peek a connection's SNI
+// before picking a backend) doesn't have to duplicate it.
 func extractSNI(payload []byte) string {
-	// This is a simplified parser. Robust one would use cryptobyte or similar.
-	// Structure:
-	// Record Header (5 bytes)
-	// Handshake Header (4 bytes)
-	// Client Version (2 bytes)
-	// Client Random (32 bytes)
-	// Session ID Len (1 byte)
-	// Session ID (variable)
-	// Cipher Suites Len (2 bytes)
-	// Cipher Suites (variable)
-	// Compression Methods Len (1 byte)
-	// Compression Methods (variable)
-	// Extensions Len (2 bytes)
-	// Extensions (variable)
+	return capture.ExtractSNI(payload)
+}
 
+// parseClientHello walks the same Client Hello structure as extractSNI but
+// collects every field JA3 needs (version, cipher suites, extensions,
+// elliptic curves and point formats) instead of stopping at the SNI
+// extension.
+func parseClientHello(payload []byte) (capture.ClientHello, bool) {
+	var ch capture.ClientHello
 	if len(payload) < 43 {
-		return ""
+		return ch, false
 	}
 
-	offset := 5 + 4 // Skip Record + Handshake headers
+	ch.Version = uint16(payload[9])<<8 | uint16(payload[10]) // client_version, right after the handshake header
+
+	offset := 5 + 4 // Record + Handshake headers
 	offset += 2     // Version
 	offset += 32    // Random
 
 	if offset >= len(payload) {
-		return ""
+		return ch, false
 	}
 	sessIDLen := int(payload[offset])
 	offset += 1 + sessIDLen
 
 	if offset+2 > len(payload) {
-		return ""
+		return ch, false
 	}
 	cipherSuitesLen := int(payload[offset])<<8 | int(payload[offset+1])
-	offset += 2 + cipherSuitesLen
+	offset += 2
+	ch.CipherSuites = readUint16List(payload, offset, cipherSuitesLen)
+	offset += cipherSuitesLen
 
 	if offset+1 > len(payload) {
-		return ""
+		return ch, false
 	}
 	compMethodsLen := int(payload[offset])
 	offset += 1 + compMethodsLen
 
 	if offset+2 > len(payload) {
-		return ""
+		return ch, false
 	}
 	extensionsLen := int(payload[offset])<<8 | int(payload[offset+1])
 	offset += 2
@@ -189,28 +340,94 @@ func extractSNI(payload []byte) string {
 	for offset+4 <= end {
 		extType := int(payload[offset])<<8 | int(payload[offset+1])
 		extLen := int(payload[offset+2])<<8 | int(payload[offset+3])
-		offset += 4
+		extStart := offset + 4
+		if extStart+extLen > end {
+			break
+		}
+		ch.Extensions = append(ch.Extensions, uint16(extType))
 
-		if extType == 0 { // Server Name Indication
-			if offset+extLen > end {
-				return ""
-			}
-			// SNI structure:
-			// List Length (2 bytes)
-			// Type (1 byte) (0 = host_name)
-			// Length (2 bytes)
-			// HostName (variable)
-			if extLen < 5 {
-				return ""
+		switch extType {
+		case 10: // supported_groups (elliptic curves)
+			if extLen >= 2 {
+				listLen := int(payload[extStart])<<8 | int(payload[extStart+1])
+				ch.EllipticCurves = readUint16List(payload, extStart+2, listLen)
 			}
-			sniLen := int(payload[offset+3])<<8 | int(payload[offset+4])
-			if offset+5+sniLen > end {
-				return ""
+		case 11: // ec_point_formats
+			if extLen >= 1 {
+				listLen := int(payload[extStart])
+				for i := 0; i < listLen && extStart+1+i < extStart+extLen; i++ {
+					ch.PointFormats = append(ch.PointFormats, uint16(payload[extStart+1+i]))
+				}
 			}
-			return string(payload[offset+5 : offset+5+sniLen])
 		}
-		offset += extLen
+
+		offset = extStart + extLen
+	}
+
+	return ch, true
+}
+
+// parseServerHello extracts the version, negotiated cipher suite and
+// extension list from a Server Hello for JA3S fingerprinting.
+func parseServerHello(payload []byte) (capture.ServerHello, bool) {
+	var sh capture.ServerHello
+	if len(payload) < 43 {
+		return sh, false
+	}
+
+	offset := 5 + 4 // Record + Handshake headers
+	sh.Version = uint16(payload[offset])<<8 | uint16(payload[offset+1])
+	offset += 2  // Version
+	offset += 32 // Random
+
+	if offset >= len(payload) {
+		return sh, false
+	}
+	sessIDLen := int(payload[offset])
+	offset += 1 + sessIDLen
+
+	if offset+2 > len(payload) {
+		return sh, false
+	}
+	sh.CipherSuite = uint16(payload[offset])<<8 | uint16(payload[offset+1])
+	offset += 2
+
+	if offset+1 > len(payload) {
+		return sh, false
+	}
+	offset++ // Compression method
+
+	if offset+2 > len(payload) {
+		return sh, true // extensions are optional on a Server Hello
+	}
+	extensionsLen := int(payload[offset])<<8 | int(payload[offset+1])
+	offset += 2
+
+	end := offset + extensionsLen
+	if end > len(payload) {
+		end = len(payload)
+	}
+	for offset+4 <= end {
+		extType := int(payload[offset])<<8 | int(payload[offset+1])
+		extLen := int(payload[offset+2])<<8 | int(payload[offset+3])
+		sh.Extensions = append(sh.Extensions, uint16(extType))
+		offset += 4 + extLen
 	}
 
-	return ""
+	return sh, true
+}
+
+// readUint16List decodes n bytes starting at offset as a sequence of
+// big-endian uint16s, as used by both the cipher suite and elliptic curve
+// lists in a Client Hello.
+func readUint16List(payload []byte, offset, n int) []uint16 {
+	out := make([]uint16, 0, n/2)
+	end := offset + n
+	if end > len(payload) {
+		end = len(payload)
+	}
+	for i := offset; i+2 <= end; i += 2 {
+		out = append(out, uint16(payload[i])<<8|uint16(payload[i+1]))
+	}
+	return out
 }
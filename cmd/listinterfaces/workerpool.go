@@ -0,0 +1,189 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/tcpassembly"
+
+	"github.com/yalefresne/streamlog/internal/reassembly"
+)
+
+// reassemblyPorts are the TCP ports whose payload gets buffered through
+// the reassembler instead of inspected one segment at a time, since a
+// Client Hello on any of them can span multiple segments.
+var reassemblyPorts = map[layers.TCPPort]bool{
+	443: true, // HTTPS
+	853: true, // DNS-over-TLS
+}
+
+// streamTimeout bounds how long a stream direction can sit idle before
+// its reassembly buffer is flushed and released.
+const streamTimeout = 10 * time.Second
+
+// frame is a single raw capture paired with the timestamp libpcap/AF_PACKET
+// recorded for it, which the TCP reassembler needs to order segments and
+// age out idle streams.
+type frame struct {
+	data []byte
+	seen time.Time
+}
+
+// workerPool fans raw frames out across a fixed number of decode workers.
+// Frames are sharded by flow (source/destination address) rather than
+// round-robined, so every packet belonging to a given TCP or UDP flow is
+// always handled by the same worker and therefore stays in order.
+type workerPool struct {
+	shards []chan frame
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(n, queueSize int) *workerPool {
+	if n < 1 {
+		n = 1
+	}
+	p := &workerPool{shards: make([]chan frame, n)}
+	for i := range p.shards {
+		p.shards[i] = make(chan frame, queueSize)
+	}
+	return p
+}
+
+// start launches one decode goroutine per shard.
+func (p *workerPool) start() {
+	p.wg.Add(len(p.shards))
+	for _, shard := range p.shards {
+		shard := shard
+		go func() {
+			defer p.wg.Done()
+			decodeWorker(shard)
+		}()
+	}
+}
+
+// stop closes every shard and waits for each decode goroutine to drain its
+// queued frames and flush its assembler, so callers can rely on every
+// frame submitted before stop being fully processed by the time it
+// returns.
+func (p *workerPool) stop() {
+	for _, shard := range p.shards {
+		close(shard)
+	}
+	p.wg.Wait()
+}
+
+// submit hands a raw frame to the worker responsible for its flow.
+func (p *workerPool) submit(data []byte, seen time.Time) {
+	p.shards[flowShard(data, len(p.shards))] <- frame{data: data, seen: seen}
+}
+
+// flowShard picks a worker index from the IPv4/IPv6 source and destination
+// addresses embedded in the raw Ethernet frame, so frames can be routed to
+// a worker without first paying for a full layer decode.
+func flowShard(data []byte, n int) int {
+	if n <= 1 || len(data) < 14 {
+		return 0
+	}
+	h := fnv.New32a()
+	switch {
+	case data[12] == 0x08 && data[13] == 0x00 && len(data) >= 34: // IPv4
+		h.Write(data[26:34]) // src + dst addresses
+	case data[12] == 0x86 && data[13] == 0xdd && len(data) >= 54: // IPv6
+		h.Write(data[22:54]) // src + dst addresses
+	default:
+		return 0
+	}
+	return int(h.Sum32() % uint32(n))
+}
+
+// decodeWorker owns a single DecodingLayerParser (and its target layers),
+// so frames are decoded without allocating a fresh gopacket.Packet, and a
+// single tcpassembly.Assembler that reassembles the TLS ports of interest.
+// Both are only ever touched from this goroutine, so neither needs its own
+// locking.
+func decodeWorker(frames <-chan frame) {
+	var (
+		eth     layers.Ethernet
+		ip4     layers.IPv4
+		ip6     layers.IPv6
+		tcp     layers.TCP
+		udp     layers.UDP
+		dns     layers.DNS
+		payload gopacket.Payload
+	)
+	parser := gopacket.NewDecodingLayerParser(
+		layers.LayerTypeEthernet,
+		&eth, &ip4, &ip6, &tcp, &udp, &dns, &payload,
+	)
+	// TCP port 443 maps to gopacket's TLS layer, which has no decoder
+	// here; without this every TLS data segment would fail to decode and
+	// never reach the assembler.
+	parser.IgnoreUnsupported = true
+	decoded := make([]gopacket.LayerType, 0, 7)
+
+	pool := tcpassembly.NewStreamPool(reassembly.NewFactory(handleReassembled))
+	assembler := tcpassembly.NewAssembler(pool)
+	lastFlush := time.Now()
+
+	for f := range frames {
+		if err := parser.DecodeLayers(f.data, &decoded); err != nil {
+			// Truncated or malformed frame; skip and keep the stream alive.
+			continue
+		}
+
+		var sawTCP, sawIP4, sawIP6 bool
+		for _, lt := range decoded {
+			switch lt {
+			case layers.LayerTypeTCP:
+				sawTCP = true
+			case layers.LayerTypeIPv4:
+				sawIP4 = true
+			case layers.LayerTypeIPv6:
+				sawIP6 = true
+			case layers.LayerTypeDNS:
+				processDNS(&dns)
+			}
+		}
+
+		if sawTCP && (reassemblyPorts[tcp.SrcPort] || reassemblyPorts[tcp.DstPort]) {
+			switch {
+			case sawIP4:
+				assembler.AssembleWithTimestamp(ip4.NetworkFlow(), &tcp, f.seen)
+			case sawIP6:
+				assembler.AssembleWithTimestamp(ip6.NetworkFlow(), &tcp, f.seen)
+			}
+		}
+
+		if time.Since(lastFlush) > streamTimeout/2 {
+			assembler.FlushOlderThan(f.seen.Add(-streamTimeout))
+			lastFlush = time.Now()
+		}
+	}
+
+	// frames is closed once the capture loop stops (signal or EOF); give
+	// every stream still buffered a final chance to reach
+	// handleReassembled instead of discarding it.
+	assembler.FlushAll()
+}
+
+// handleReassembled is the reassembly.Handler for every tracked stream
+// direction: once a stream completes or hits its buffer cap, this parses
+// whatever was collected for a TLS Client Hello / Server Hello.
+func handleReassembled(netFlow, transportFlow gopacket.Flow, payload []byte) {
+	src, dst := netFlow.Endpoints()
+	srcPort, dstPort := transportFlow.Endpoints()
+	processTLS(src.String(), dst.String(), endpointPort(srcPort), endpointPort(dstPort), payload)
+}
+
+// endpointPort decodes a gopacket.Endpoint created from a layers.TCPPort
+// back into its raw uint16 value.
+func endpointPort(ep gopacket.Endpoint) uint16 {
+	raw := ep.Raw()
+	if len(raw) != 2 {
+		return 0
+	}
+	return uint16(raw[0])<<8 | uint16(raw[1])
+}
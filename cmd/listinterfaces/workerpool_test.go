@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+// ipv4Frame builds a minimal Ethernet+IPv4 frame with only the header
+// fields flowShard reads: the EtherType and the source/destination
+// addresses.
+func ipv4Frame(t *testing.T, src, dst string) []byte {
+	t.Helper()
+	data := make([]byte, 34)
+	data[12], data[13] = 0x08, 0x00
+	copy(data[26:30], net.ParseIP(src).To4())
+	copy(data[30:34], net.ParseIP(dst).To4())
+	return data
+}
+
+// ipv6Frame is ipv4Frame's IPv6 counterpart.
+func ipv6Frame(t *testing.T, src, dst string) []byte {
+	t.Helper()
+	data := make([]byte, 54)
+	data[12], data[13] = 0x86, 0xdd
+	copy(data[22:38], net.ParseIP(src).To16())
+	copy(data[38:54], net.ParseIP(dst).To16())
+	return data
+}
+
+// TestFlowShard_ipv4SameFlowSameShard verifies that two frames belonging
+// to the same IPv4 flow hash to the same shard, so a single worker always
+// sees every packet for that flow in order.
+func TestFlowShard_ipv4SameFlowSameShard(t *testing.T) {
+	a := ipv4Frame(t, "10.0.0.1", "10.0.0.2")
+	b := ipv4Frame(t, "10.0.0.1", "10.0.0.2")
+
+	if flowShard(a, 8) != flowShard(b, 8) {
+		t.Errorf("expected identical IPv4 flows to hash to the same shard")
+	}
+}
+
+// TestFlowShard_ipv6SameFlowSameShard is TestFlowShard_ipv4SameFlowSameShard's
+// IPv6 counterpart.
+func TestFlowShard_ipv6SameFlowSameShard(t *testing.T) {
+	a := ipv6Frame(t, "2001:db8::1", "2001:db8::2")
+	b := ipv6Frame(t, "2001:db8::1", "2001:db8::2")
+
+	if flowShard(a, 8) != flowShard(b, 8) {
+		t.Errorf("expected identical IPv6 flows to hash to the same shard")
+	}
+}
+
+// TestFlowShard_unrecognizedEtherTypeFallsBackToZero ensures a frame whose
+// EtherType is neither IPv4 nor IPv6 (e.g. ARP) doesn't hash garbage bytes
+// and instead falls back to shard 0.
+func TestFlowShard_unrecognizedEtherTypeFallsBackToZero(t *testing.T) {
+	data := make([]byte, 20)
+	data[12], data[13] = 0x08, 0x06 // ARP
+
+	if got := flowShard(data, 8); got != 0 {
+		t.Errorf("expected unrecognized EtherType to fall back to shard 0, got %d", got)
+	}
+}
+
+// TestFlowShard_singleWorkerAlwaysZero checks the n<=1 short-circuit that
+// lets callers skip hashing entirely when there's only one worker.
+func TestFlowShard_singleWorkerAlwaysZero(t *testing.T) {
+	data := ipv4Frame(t, "10.0.0.1", "10.0.0.2")
+
+	if got := flowShard(data, 1); got != 0 {
+		t.Errorf("expected n<=1 to always return shard 0, got %d", got)
+	}
+}
+
+// TestFlowShard_tooShortFallsBackToZero ensures a frame too short to hold
+// an Ethernet header doesn't panic and falls back to shard 0.
+func TestFlowShard_tooShortFallsBackToZero(t *testing.T) {
+	data := make([]byte, 10)
+
+	if got := flowShard(data, 8); got != 0 {
+		t.Errorf("expected a too-short frame to fall back to shard 0, got %d", got)
+	}
+}
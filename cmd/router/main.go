@@ -0,0 +1,28 @@
+// router runs streamlog's SNI-aware TCP router: it peeks the Client
+// Hello on every connection accepted on -addr and splices it to whichever
+// backend its ruleset picks, without ever terminating TLS. The ruleset is
+// reloaded on SIGHUP.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/yalefresne/streamlog/internal/router"
+)
+
+func main() {
+	addr := flag.String("addr", ":443", "address to listen on")
+	configPath := flag.String("config", "router.yaml", "path to the routing ruleset (YAML or JSON)")
+	flag.Parse()
+
+	rt, err := router.New(*configPath)
+	if err != nil {
+		log.Fatalf("Error loading %s: %v", *configPath, err)
+	}
+	rt.WatchReload()
+
+	proxy := &router.Proxy{Router: rt}
+	log.Printf("Listening on %s, routing by SNI per %s", *addr, *configPath)
+	log.Fatal(proxy.ListenAndServe(*addr))
+}
@@ -0,0 +1,121 @@
+package capture
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+// Certificate is a structured summary of a single X.509 certificate
+// observed in a TLS Server Hello's Certificate message.
+type Certificate struct {
+	Subject      string
+	Issuer       string
+	SANs         []string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	SHA256       string
+	SerialNumber string
+
+	// DER holds the raw certificate bytes, e.g. for dumping to disk.
+	DER []byte
+}
+
+// ExtractCertificates walks the TLS records buffered for a server-direction
+// stream looking for a Certificate handshake message (handshake type 11)
+// and parses every DER certificate in its chain. It returns (nil, nil) if
+// no Certificate message is present, which is the common case for the
+// client->server direction.
+func ExtractCertificates(payload []byte) ([]Certificate, error) {
+	certs, _ := certificatesFromHandshakeStream(handshakeBytes(payload))
+	return certs, nil
+}
+
+// handshakeBytes strips the 5-byte TLS record header from every Handshake
+// (content type 22) record in payload and concatenates their bodies into a
+// single contiguous stream. A handshake message - a Certificate message
+// carrying a large chain, say - can span more than one TLS record; this
+// lets certificatesFromHandshakeStream parse the handshake layer without
+// caring where the underlying record boundaries fell.
+func handshakeBytes(payload []byte) []byte {
+	var handshake []byte
+	offset := 0
+	for offset+5 <= len(payload) {
+		contentType := payload[offset]
+		recordLen := int(payload[offset+3])<<8 | int(payload[offset+4])
+		body := offset + 5
+		if body+recordLen > len(payload) {
+			break
+		}
+		if contentType == 22 { // Handshake
+			handshake = append(handshake, payload[body:body+recordLen]...)
+		}
+		offset = body + recordLen
+	}
+	return handshake
+}
+
+// certificatesFromHandshakeStream scans a contiguous handshake-message
+// stream (see handshakeBytes) for a Certificate message (type 11).
+func certificatesFromHandshakeStream(stream []byte) ([]Certificate, bool) {
+	offset := 0
+	for offset+4 <= len(stream) {
+		msgType := stream[offset]
+		msgLen := int(stream[offset+1])<<16 | int(stream[offset+2])<<8 | int(stream[offset+3])
+		body := offset + 4
+		if body+msgLen > len(stream) {
+			// The message continues past what we've buffered so far;
+			// nothing more to find until later records arrive.
+			return nil, false
+		}
+		if msgType == 11 {
+			certs := parseCertificateMessage(stream[body : body+msgLen])
+			return certs, len(certs) > 0
+		}
+		offset = body + msgLen
+	}
+	return nil, false
+}
+
+// parseCertificateMessage decodes the 3-byte-length-prefixed chain of DER
+// certificates carried by a Certificate handshake message body.
+func parseCertificateMessage(body []byte) []Certificate {
+	if len(body) < 3 {
+		return nil
+	}
+	chainLen := int(body[0])<<16 | int(body[1])<<8 | int(body[2])
+	offset := 3
+	end := offset + chainLen
+	if end > len(body) {
+		end = len(body)
+	}
+
+	var certs []Certificate
+	for offset+3 <= end {
+		certLen := int(body[offset])<<16 | int(body[offset+1])<<8 | int(body[offset+2])
+		offset += 3
+		if offset+certLen > end {
+			break
+		}
+		der := append([]byte(nil), body[offset:offset+certLen]...)
+		offset += certLen
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(der)
+		certs = append(certs, Certificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SANs:         cert.DNSNames,
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			SHA256:       hex.EncodeToString(sum[:]),
+			SerialNumber: cert.SerialNumber.String(),
+			DER:          der,
+		})
+	}
+	return certs
+}
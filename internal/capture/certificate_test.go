@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// certificateHandshakeMessage builds the Certificate handshake message body
+// (type + length header included) for a single-certificate chain wrapping
+// der, mirroring what a real Server Hello -> Certificate exchange carries.
+func certificateHandshakeMessage(der []byte) []byte {
+	cert := make([]byte, 3+len(der))
+	cert[0], cert[1], cert[2] = byte(len(der)>>16), byte(len(der)>>8), byte(len(der))
+	copy(cert[3:], der)
+
+	chainLen := len(cert)
+	body := make([]byte, 3+chainLen)
+	body[0], body[1], body[2] = byte(chainLen>>16), byte(chainLen>>8), byte(chainLen)
+	copy(body[3:], cert)
+
+	msg := make([]byte, 4+len(body))
+	msg[0] = 11 // Certificate
+	msg[1], msg[2], msg[3] = byte(len(body)>>16), byte(len(body)>>8), byte(len(body))
+	copy(msg[4:], body)
+	return msg
+}
+
+// wrapHandshakeRecord wraps msg in a single TLS Handshake record header.
+func wrapHandshakeRecord(msg []byte) []byte {
+	record := make([]byte, 5+len(msg))
+	record[0] = 22 // Handshake
+	record[1], record[2] = 3, 3
+	record[3], record[4] = byte(len(msg)>>8), byte(len(msg))
+	copy(record[5:], msg)
+	return record
+}
+
+// buildCertificateRecord wraps der as a single-certificate Certificate
+// handshake message inside a single TLS record, mirroring what a real
+// Server Hello -> Certificate exchange looks like on the wire.
+func buildCertificateRecord(der []byte) []byte {
+	return wrapHandshakeRecord(certificateHandshakeMessage(der))
+}
+
+// buildSplitCertificateRecords wraps der as a single-certificate
+// Certificate handshake message but splits it across two separate TLS
+// records, as happens on the wire once a chain is too large for one
+// record.
+func buildSplitCertificateRecords(der []byte) []byte {
+	msg := certificateHandshakeMessage(der)
+	split := len(msg) / 2
+
+	payload := wrapHandshakeRecord(msg[:split])
+	payload = append(payload, wrapHandshakeRecord(msg[split:])...)
+	return payload
+}
+
+func selfSignedDER(t *testing.T, commonName string) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	return der
+}
+
+// TestExtractCertificates_parsesChain checks that a Certificate message
+// wrapped in a TLS record round-trips back into a Certificate with the
+// expected fields.
+func TestExtractCertificates_parsesChain(t *testing.T) {
+	der := selfSignedDER(t, "example.com")
+	payload := buildCertificateRecord(der)
+
+	certs, err := ExtractCertificates(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(certs))
+	}
+
+	got := certs[0]
+	if got.Subject != "CN=example.com" {
+		t.Errorf("Subject: want %q, got %q", "CN=example.com", got.Subject)
+	}
+	if len(got.SANs) != 1 || got.SANs[0] != "example.com" {
+		t.Errorf("SANs: want [example.com], got %v", got.SANs)
+	}
+	if got.SerialNumber != "42" {
+		t.Errorf("SerialNumber: want %q, got %q", "42", got.SerialNumber)
+	}
+	if len(got.SHA256) != 64 {
+		t.Errorf("SHA256: want 64 hex chars, got %d", len(got.SHA256))
+	}
+}
+
+// TestExtractCertificates_splitAcrossRecords verifies that a Certificate
+// handshake message split across two TLS records - as happens once a
+// chain is larger than one record - is still reassembled and parsed
+// correctly instead of being silently dropped.
+func TestExtractCertificates_splitAcrossRecords(t *testing.T) {
+	der := selfSignedDER(t, "split.example.com")
+	payload := buildSplitCertificateRecords(der)
+
+	certs, err := ExtractCertificates(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(certs))
+	}
+	if certs[0].Subject != "CN=split.example.com" {
+		t.Errorf("Subject: want %q, got %q", "CN=split.example.com", certs[0].Subject)
+	}
+}
+
+// TestExtractCertificates_noCertificateMessage ensures a record without a
+// Certificate handshake message (e.g. a Client Hello) is reported as
+// "nothing found" rather than an error.
+func TestExtractCertificates_noCertificateMessage(t *testing.T) {
+	record := []byte{22, 3, 3, 0, 4, 1 /* Client Hello */, 0, 0, 0}
+
+	certs, err := ExtractCertificates(record)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if certs != nil {
+		t.Errorf("expected no certificates, got %v", certs)
+	}
+}
@@ -0,0 +1,101 @@
+package capture
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// TLSFingerprint is a JA3/JA3S pair captured for a single TLS handshake,
+// keyed to the flow it was observed on.
+type TLSFingerprint struct {
+	SNI string
+
+	JA3     string
+	JA3Hash string
+
+	JA3S     string
+	JA3SHash string
+
+	SrcIP   string
+	DstIP   string
+	SrcPort uint16
+	DstPort uint16
+}
+
+// ClientHello holds the fields of a TLS Client Hello that feed into a JA3
+// fingerprint.
+type ClientHello struct {
+	Version        uint16
+	CipherSuites   []uint16
+	Extensions     []uint16
+	EllipticCurves []uint16
+	PointFormats   []uint16
+	SNI            string
+}
+
+// ServerHello holds the fields of a TLS Server Hello that feed into a JA3S
+// fingerprint.
+type ServerHello struct {
+	Version     uint16
+	CipherSuite uint16
+	Extensions  []uint16
+}
+
+// isGREASE reports whether v is one of the reserved GREASE values
+// (RFC 8701, e.g. 0x0a0a, 0x1a1a, ... 0xfafa). GREASE values are random
+// per-connection filler and must be stripped before fingerprinting,
+// otherwise every client with GREASE enabled produces a unique JA3.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && (v>>8) == (v&0xff)
+}
+
+// JA3 computes the JA3 fingerprint string and its MD5 hash for a Client
+// Hello: "TLSVersion,CipherSuites,Extensions,EllipticCurves,EllipticCurvePointFormats"
+// with GREASE values filtered out of every list.
+func JA3(ch ClientHello) (string, string) {
+	s := strings.Join([]string{
+		strconv.Itoa(int(ch.Version)),
+		joinUint16(filterGREASE(ch.CipherSuites)),
+		joinUint16(filterGREASE(ch.Extensions)),
+		joinUint16(filterGREASE(ch.EllipticCurves)),
+		joinUint16(filterGREASE(ch.PointFormats)),
+	}, ",")
+	return s, md5Hex(s)
+}
+
+// JA3S computes the JA3S fingerprint string and its MD5 hash for a Server
+// Hello: "TLSVersion,CipherSuite,Extensions" with GREASE values filtered
+// out of the extensions list.
+func JA3S(sh ServerHello) (string, string) {
+	s := strings.Join([]string{
+		strconv.Itoa(int(sh.Version)),
+		strconv.Itoa(int(sh.CipherSuite)),
+		joinUint16(filterGREASE(sh.Extensions)),
+	}, ",")
+	return s, md5Hex(s)
+}
+
+func filterGREASE(in []uint16) []uint16 {
+	out := make([]uint16, 0, len(in))
+	for _, v := range in {
+		if !isGREASE(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func joinUint16(in []uint16) string {
+	parts := make([]string, len(in))
+	for i, v := range in {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, "-")
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package capture
+
+import "testing"
+
+// TestJA3_knownVector checks the JA3 string format and hash against a
+// small, hand-built Client Hello with no GREASE values.
+func TestJA3_knownVector(t *testing.T) {
+	ch := ClientHello{
+		Version:        771,
+		CipherSuites:   []uint16{4865, 4866, 4867},
+		Extensions:     []uint16{0, 23, 65281},
+		EllipticCurves: []uint16{29, 23, 24},
+		PointFormats:   []uint16{0},
+	}
+
+	s, hash := JA3(ch)
+
+	wantString := "771,4865-4866-4867,0-23-65281,29-23-24,0"
+	if s != wantString {
+		t.Errorf("JA3 string: want %q, got %q", wantString, s)
+	}
+	if len(hash) != 32 {
+		t.Errorf("JA3 hash: want 32 hex chars, got %d (%q)", len(hash), hash)
+	}
+}
+
+// TestJA3_stripsGREASE ensures GREASE values are filtered out of the
+// cipher suite, extension and curve lists before hashing, per RFC 8701.
+func TestJA3_stripsGREASE(t *testing.T) {
+	withGREASE := ClientHello{
+		Version:        771,
+		CipherSuites:   []uint16{0x0a0a, 4865, 0x1a1a, 4866},
+		Extensions:     []uint16{0x2a2a, 0, 23},
+		EllipticCurves: []uint16{0x3a3a, 29, 23},
+		PointFormats:   []uint16{0x0a0a, 0},
+	}
+	clean := ClientHello{
+		Version:        771,
+		CipherSuites:   []uint16{4865, 4866},
+		Extensions:     []uint16{0, 23},
+		EllipticCurves: []uint16{29, 23},
+		PointFormats:   []uint16{0},
+	}
+
+	gotStr, gotHash := JA3(withGREASE)
+	wantStr, wantHash := JA3(clean)
+
+	if gotStr != wantStr {
+		t.Errorf("JA3 string with GREASE: want %q, got %q", wantStr, gotStr)
+	}
+	if gotHash != wantHash {
+		t.Errorf("JA3 hash with GREASE: want %q, got %q", wantHash, gotHash)
+	}
+}
+
+// TestJA3S_knownVector checks the JA3S string format for a Server Hello.
+func TestJA3S_knownVector(t *testing.T) {
+	sh := ServerHello{
+		Version:     771,
+		CipherSuite: 4865,
+		Extensions:  []uint16{0x0a0a, 0, 23},
+	}
+
+	s, hash := JA3S(sh)
+
+	wantString := "771,4865,0-23"
+	if s != wantString {
+		t.Errorf("JA3S string: want %q, got %q", wantString, s)
+	}
+	if len(hash) != 32 {
+		t.Errorf("JA3S hash: want 32 hex chars, got %d (%q)", len(hash), hash)
+	}
+}
+
+// TestIsGREASE covers the full set of reserved GREASE values plus a
+// handful of ordinary values that must not be misidentified.
+func TestIsGREASE(t *testing.T) {
+	greaseValues := []uint16{0x0a0a, 0x1a1a, 0x2a2a, 0x3a3a, 0xfafa}
+	for _, v := range greaseValues {
+		if !isGREASE(v) {
+			t.Errorf("isGREASE(0x%04x): want true, got false", v)
+		}
+	}
+
+	notGREASE := []uint16{771, 4865, 0, 23, 65281}
+	for _, v := range notGREASE {
+		if isGREASE(v) {
+			t.Errorf("isGREASE(0x%04x): want false, got true", v)
+		}
+	}
+}
@@ -2,7 +2,7 @@
 // and capturing packets using libpcap via the gopacket library.
 package capture
 
-import "github.com/google/gopacket/pcap"
+import "github.com/gopacket/gopacket/pcap"
 
 // Interface holds the metadata we care about for a network interface.
 type Interface struct {
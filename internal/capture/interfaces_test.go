@@ -4,7 +4,7 @@ import (
 	"net"
 	"testing"
 
-	"github.com/google/gopacket/pcap"
+	"github.com/gopacket/gopacket/pcap"
 )
 
 // TestFindInterfaces_mapsFields verifies that FindInterfaces correctly
@@ -0,0 +1,85 @@
+package capture
+
+// ExtractSNI parses a TLS Client Hello record and returns the host_name
+// carried by its Server Name Indication extension, or "" if the record is
+// truncated, malformed, or simply has no SNI extension.
+func ExtractSNI(payload []byte) string {
+	// Structure:
+	// Record Header (5 bytes)
+	// Handshake Header (4 bytes)
+	// Client Version (2 bytes)
+	// Client Random (32 bytes)
+	// Session ID Len (1 byte)
+	// Session ID (variable)
+	// Cipher Suites Len (2 bytes)
+	// Cipher Suites (variable)
+	// Compression Methods Len (1 byte)
+	// Compression Methods (variable)
+	// Extensions Len (2 bytes)
+	// Extensions (variable)
+
+	if len(payload) < 43 {
+		return ""
+	}
+
+	offset := 5 + 4 // Skip Record + Handshake headers
+	offset += 2     // Version
+	offset += 32    // Random
+
+	if offset >= len(payload) {
+		return ""
+	}
+	sessIDLen := int(payload[offset])
+	offset += 1 + sessIDLen
+
+	if offset+2 > len(payload) {
+		return ""
+	}
+	cipherSuitesLen := int(payload[offset])<<8 | int(payload[offset+1])
+	offset += 2 + cipherSuitesLen
+
+	if offset+1 > len(payload) {
+		return ""
+	}
+	compMethodsLen := int(payload[offset])
+	offset += 1 + compMethodsLen
+
+	if offset+2 > len(payload) {
+		return ""
+	}
+	extensionsLen := int(payload[offset])<<8 | int(payload[offset+1])
+	offset += 2
+
+	end := offset + extensionsLen
+	if end > len(payload) {
+		end = len(payload)
+	}
+
+	for offset+4 <= end {
+		extType := int(payload[offset])<<8 | int(payload[offset+1])
+		extLen := int(payload[offset+2])<<8 | int(payload[offset+3])
+		offset += 4
+
+		if extType == 0 { // Server Name Indication
+			if offset+extLen > end {
+				return ""
+			}
+			// SNI structure:
+			// List Length (2 bytes)
+			// Type (1 byte) (0 = host_name)
+			// Length (2 bytes)
+			// HostName (variable)
+			if extLen < 5 {
+				return ""
+			}
+			sniLen := int(payload[offset+3])<<8 | int(payload[offset+4])
+			if offset+5+sniLen > end {
+				return ""
+			}
+			return string(payload[offset+5 : offset+5+sniLen])
+		}
+		offset += extLen
+	}
+
+	return ""
+}
@@ -0,0 +1,55 @@
+package capture
+
+import "testing"
+
+// buildClientHello assembles a minimal Client Hello record. If hostname
+// is non-empty it carries a single SNI extension for it; otherwise the
+// record has no extensions at all.
+func buildClientHello(hostname string) []byte {
+	var ext []byte
+	if hostname != "" {
+		entry := append([]byte{0}, byte(len(hostname)>>8), byte(len(hostname)))
+		entry = append(entry, hostname...)
+		sni := append([]byte{byte(len(entry) >> 8), byte(len(entry))}, entry...)
+		ext = append([]byte{0, 0, byte(len(sni) >> 8), byte(len(sni))}, sni...)
+	}
+
+	body := make([]byte, 0, 64)
+	body = append(body, 3, 3)                // client version
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0)                   // session id len
+	body = append(body, 0, 0)                // cipher suites len
+	body = append(body, 0)                   // compression methods len
+	body = append(body, byte(len(ext)>>8), byte(len(ext)))
+	body = append(body, ext...)
+
+	handshake := append([]byte{1, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body...)
+	record := append([]byte{22, 3, 3, byte(len(handshake) >> 8), byte(len(handshake))}, handshake...)
+	return record
+}
+
+// TestExtractSNI_happyPath checks that a well-formed Client Hello yields
+// its SNI hostname.
+func TestExtractSNI_happyPath(t *testing.T) {
+	record := buildClientHello("example.com")
+	if got := ExtractSNI(record); got != "example.com" {
+		t.Errorf("ExtractSNI: want %q, got %q", "example.com", got)
+	}
+}
+
+// TestExtractSNI_truncated ensures a too-short payload returns "" rather
+// than panicking.
+func TestExtractSNI_truncated(t *testing.T) {
+	if got := ExtractSNI([]byte{22, 3, 3, 0, 1, 1}); got != "" {
+		t.Errorf("ExtractSNI(truncated): want \"\", got %q", got)
+	}
+}
+
+// TestExtractSNI_noSNIExtension ensures a Client Hello with no extensions
+// at all returns "" instead of an error.
+func TestExtractSNI_noSNIExtension(t *testing.T) {
+	record := buildClientHello("")
+	if got := ExtractSNI(record); got != "" {
+		t.Errorf("ExtractSNI(no SNI): want \"\", got %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package capture
+
+import "testing"
+
+// TestCompileRawBPF_validFilter checks that a well-formed BPF expression
+// compiles to at least one classic BPF instruction, without needing a
+// live device (libpcap's offline compiler only needs a link type and
+// snap length).
+func TestCompileRawBPF_validFilter(t *testing.T) {
+	raw, err := compileRawBPF("udp port 53 or tcp port 443")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(raw) == 0 {
+		t.Error("expected at least one compiled instruction")
+	}
+}
+
+// TestCompileRawBPF_invalidFilter checks that a malformed expression is
+// reported as an error rather than panicking or returning an empty
+// program.
+func TestCompileRawBPF_invalidFilter(t *testing.T) {
+	if _, err := compileRawBPF("not a valid bpf expression((("); err == nil {
+		t.Error("expected an error for a malformed filter")
+	}
+}
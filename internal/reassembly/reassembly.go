@@ -0,0 +1,150 @@
+// Package reassembly buffers TCP streams long enough to recover an
+// application-layer message (such as a TLS Client Hello) that may have
+// been split across multiple segments, then hands the reassembled bytes
+// to a caller-supplied handler. It is a thin adapter over
+// gopacket/tcpassembly that adds a buffer cap per stream and a cap on the
+// number of streams tracked at once, so it stays safe to run against
+// adversarial or simply very busy traffic.
+package reassembly
+
+import (
+	"sync"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/tcpassembly"
+)
+
+const (
+	// DefaultMaxBuffer is how many bytes of a single stream direction we
+	// buffer before giving up on ever seeing a complete handshake record.
+	DefaultMaxBuffer = 16 * 1024
+
+	// DefaultMaxStreams bounds the number of concurrently tracked stream
+	// directions, so a connection flood cannot grow memory unboundedly.
+	DefaultMaxStreams = 4096
+)
+
+// Handler receives the bytes buffered for one stream direction once the
+// stream completes (FIN/RST) or its buffer cap is reached, whichever
+// happens first.
+type Handler func(netFlow, transportFlow gopacket.Flow, payload []byte)
+
+// Factory implements tcpassembly.StreamFactory. It creates a *stream for
+// every new TCP connection direction tcpassembly hands it, dropping new
+// streams once MaxStreams are already being tracked.
+type Factory struct {
+	Handler    Handler
+	MaxBuffer  int
+	MaxStreams int
+
+	mu     sync.Mutex
+	active int
+}
+
+// NewFactory returns a Factory with the package defaults; override
+// MaxBuffer/MaxStreams on the returned value before use if needed.
+func NewFactory(handler Handler) *Factory {
+	return &Factory{
+		Handler:    handler,
+		MaxBuffer:  DefaultMaxBuffer,
+		MaxStreams: DefaultMaxStreams,
+	}
+}
+
+// New implements tcpassembly.StreamFactory.
+func (f *Factory) New(netFlow, transportFlow gopacket.Flow) tcpassembly.Stream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	maxStreams := f.MaxStreams
+	if maxStreams <= 0 {
+		maxStreams = DefaultMaxStreams
+	}
+	if f.active >= maxStreams {
+		return discardStream{}
+	}
+	f.active++
+
+	maxBuffer := f.MaxBuffer
+	if maxBuffer <= 0 {
+		maxBuffer = DefaultMaxBuffer
+	}
+	return &stream{
+		factory:       f,
+		netFlow:       netFlow,
+		transportFlow: transportFlow,
+		maxBuffer:     maxBuffer,
+	}
+}
+
+func (f *Factory) release() {
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+}
+
+// discardStream is handed out once MaxStreams is reached, so the
+// assembler always has somewhere to send bytes for flows we refuse to
+// track.
+type discardStream struct{}
+
+func (discardStream) Reassembled([]tcpassembly.Reassembly) {}
+func (discardStream) ReassemblyComplete()                  {}
+
+// stream buffers up to maxBuffer bytes of a single TCP direction. It
+// tears itself down, releasing its slot back to the factory, as soon as
+// the buffer cap is hit or the stream completes.
+type stream struct {
+	factory       *Factory
+	netFlow       gopacket.Flow
+	transportFlow gopacket.Flow
+	maxBuffer     int
+
+	buf  []byte
+	done bool
+}
+
+// Reassembled implements tcpassembly.Stream.
+func (s *stream) Reassembled(reassembled []tcpassembly.Reassembly) {
+	if s.done {
+		return
+	}
+	for _, r := range reassembled {
+		if r.Skip > 0 {
+			// Bytes were lost before reassembly; anything we parse from
+			// here on could be garbage, so stop collecting for this stream.
+			s.flush()
+			return
+		}
+		room := s.maxBuffer - len(s.buf)
+		if room <= 0 {
+			s.flush()
+			return
+		}
+		chunk := r.Bytes
+		if len(chunk) > room {
+			chunk = chunk[:room]
+		}
+		s.buf = append(s.buf, chunk...)
+		if len(s.buf) >= s.maxBuffer {
+			s.flush()
+			return
+		}
+	}
+}
+
+// ReassemblyComplete implements tcpassembly.Stream.
+func (s *stream) ReassemblyComplete() {
+	s.flush()
+}
+
+func (s *stream) flush() {
+	if s.done {
+		return
+	}
+	s.done = true
+	s.factory.release()
+	if len(s.buf) > 0 && s.factory.Handler != nil {
+		s.factory.Handler(s.netFlow, s.transportFlow, s.buf)
+	}
+}
@@ -0,0 +1,100 @@
+package reassembly
+
+import (
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/tcpassembly"
+)
+
+// TestFactory_handlesCompleteStream verifies that bytes fed across several
+// Reassembled calls are concatenated and delivered to Handler once the
+// stream completes.
+func TestFactory_handlesCompleteStream(t *testing.T) {
+	var got []byte
+	f := NewFactory(func(_, _ gopacket.Flow, payload []byte) {
+		got = payload
+	})
+
+	s := f.New(gopacket.Flow{}, gopacket.Flow{})
+	s.Reassembled([]tcpassembly.Reassembly{
+		{Bytes: []byte("hello ")},
+		{Bytes: []byte("world")},
+	})
+	s.ReassemblyComplete()
+
+	if string(got) != "hello world" {
+		t.Errorf("Handler payload: want %q, got %q", "hello world", got)
+	}
+}
+
+// TestFactory_capsBufferSize ensures a stream flushes (and stops
+// accumulating) once MaxBuffer bytes have been collected, instead of
+// growing without bound.
+func TestFactory_capsBufferSize(t *testing.T) {
+	var got []byte
+	f := NewFactory(func(_, _ gopacket.Flow, payload []byte) {
+		got = payload
+	})
+	f.MaxBuffer = 4
+
+	s := f.New(gopacket.Flow{}, gopacket.Flow{})
+	s.Reassembled([]tcpassembly.Reassembly{{Bytes: []byte("abcdefgh")}})
+
+	if len(got) != 4 {
+		t.Fatalf("buffered payload: want 4 bytes, got %d (%q)", len(got), got)
+	}
+
+	// A second call after the stream already flushed must be a no-op.
+	s.Reassembled([]tcpassembly.Reassembly{{Bytes: []byte("ignored")}})
+	if len(got) != 4 {
+		t.Errorf("payload changed after flush: got %q", got)
+	}
+}
+
+// TestFactory_capsConcurrentStreams verifies that once MaxStreams streams
+// are active, New hands out a stream that silently discards bytes rather
+// than tracking more state.
+func TestFactory_capsConcurrentStreams(t *testing.T) {
+	f := NewFactory(nil)
+	f.MaxStreams = 1
+
+	first := f.New(gopacket.Flow{}, gopacket.Flow{})
+	second := f.New(gopacket.Flow{}, gopacket.Flow{})
+
+	if _, ok := second.(discardStream); !ok {
+		t.Errorf("expected second stream past MaxStreams to be a discardStream, got %T", second)
+	}
+
+	// Completing the first stream releases its slot for the next caller.
+	first.ReassemblyComplete()
+	third := f.New(gopacket.Flow{}, gopacket.Flow{})
+	if _, ok := third.(discardStream); ok {
+		t.Errorf("expected a stream after the first slot freed up, got a discardStream")
+	}
+}
+
+// TestFactory_dropsOnSkippedBytes ensures a gap reported via Reassembly.Skip
+// flushes whatever was buffered instead of silently appending past it,
+// since downstream parsing assumes a contiguous stream.
+func TestFactory_dropsOnSkippedBytes(t *testing.T) {
+	var got []byte
+	called := false
+	f := NewFactory(func(_, _ gopacket.Flow, payload []byte) {
+		called = true
+		got = payload
+	})
+
+	s := f.New(gopacket.Flow{}, gopacket.Flow{})
+	s.Reassembled([]tcpassembly.Reassembly{
+		{Bytes: []byte("partial")},
+		{Bytes: []byte("gap"), Skip: 1},
+	})
+
+	if !called {
+		t.Fatal("expected Handler to be called once a gap is seen")
+	}
+	if string(got) != "partial" {
+		t.Errorf("payload before the gap: want %q, got %q", "partial", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Package router turns streamlog into a passive-to-active SNI multiplexer:
+// it peeks the Client Hello on every accepted connection, matches the
+// hostname against a configurable ruleset, and splices the connection to
+// the chosen backend without ever terminating TLS.
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dropBackend is the sentinel Backend value that tells the proxy to close
+// the connection outright instead of dialing anywhere, e.g. a
+// `{kind: default, backend: drop}` catch-all rule.
+const dropBackend = "drop"
+
+// Rule is a single routing decision: hostnames matching Match (interpreted
+// according to Kind) are spliced to Backend. Rules are evaluated in order;
+// the first match wins.
+type Rule struct {
+	// Kind is "suffix", "regex", or "default". Match is ignored for "default".
+	Kind  string `json:"kind" yaml:"kind"`
+	Match string `json:"match" yaml:"match"`
+
+	// Backend is "host:port", "unix:/path", or the sentinel "drop" to
+	// close matching connections instead of proxying them anywhere.
+	Backend string `json:"backend" yaml:"backend"`
+}
+
+// Config is a routing ruleset as loaded from disk.
+type Config struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// LoadConfig reads a ruleset from a YAML or JSON file, chosen by its
+// extension.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	default:
+		return nil, fmt.Errorf("router: unsupported config extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("router: parsing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,165 @@
+package router
+
+import (
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/yalefresne/streamlog/internal/capture"
+)
+
+const (
+	// maxClientHelloPeek bounds how many bytes we'll buffer while waiting
+	// for a complete Client Hello record before giving up on a
+	// connection.
+	maxClientHelloPeek = 16 * 1024
+
+	// clientHelloTimeout bounds how long we'll wait for a complete Client
+	// Hello to arrive before giving up on a connection, so a client that
+	// connects and then trickles bytes (or sends nothing) can't park a
+	// goroutine and file descriptor forever.
+	clientHelloTimeout = 10 * time.Second
+
+	// defaultMaxInFlight bounds how many connections can be waiting on a
+	// Client Hello or mid-splice at once, so an accept flood can't grow
+	// goroutines and file descriptors without limit.
+	defaultMaxInFlight = 4096
+)
+
+// Proxy is a passive SNI-based TCP multiplexer: it peeks the Client Hello
+// on every accepted connection, matches its SNI against Router's ruleset,
+// and splices the connection to the chosen backend, replaying the
+// buffered Client Hello bytes first.
+type Proxy struct {
+	Router *Router
+
+	// MaxInFlight caps how many connections ListenAndServe will service
+	// at once; additional accepted connections are closed immediately.
+	// Zero uses defaultMaxInFlight.
+	MaxInFlight int
+}
+
+// ListenAndServe accepts connections on addr until the listener returns
+// an error (including from being closed).
+func (p *Proxy) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	max := p.MaxInFlight
+	if max <= 0 {
+		max = defaultMaxInFlight
+	}
+	inFlight := make(chan struct{}, max)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+			go func() {
+				defer func() { <-inFlight }()
+				p.handle(conn)
+			}()
+		default:
+			conn.Close()
+		}
+	}
+}
+
+func (p *Proxy) handle(conn net.Conn) {
+	defer conn.Close()
+
+	hello, sni, err := peekClientHello(conn)
+	if err != nil {
+		log.Printf("router: %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	backend, ok := p.Router.Match(sni)
+	if !ok {
+		log.Printf("router: %s: no rule matched SNI %q", conn.RemoteAddr(), sni)
+		return
+	}
+	if backend == dropBackend {
+		log.Printf("router: %s: dropped by rule for SNI %q", conn.RemoteAddr(), sni)
+		return
+	}
+
+	upstream, err := dialBackend(backend)
+	if err != nil {
+		log.Printf("router: %s: dialing backend %q: %v", conn.RemoteAddr(), backend, err)
+		return
+	}
+	defer upstream.Close()
+
+	if _, err := upstream.Write(hello); err != nil {
+		log.Printf("router: %s: replaying Client Hello to %q: %v", conn.RemoteAddr(), backend, err)
+		return
+	}
+
+	splice(conn, upstream)
+}
+
+// dialBackend supports both "host:port" and "unix:/path" backend addresses.
+func dialBackend(backend string) (net.Conn, error) {
+	if path, ok := strings.CutPrefix(backend, "unix:"); ok {
+		return net.Dial("unix", path)
+	}
+	return net.Dial("tcp", backend)
+}
+
+// peekClientHello reads from conn until it has buffered a complete TLS
+// record containing a Client Hello, then extracts its SNI. The buffered
+// bytes are returned so they can be replayed to the backend.
+func peekClientHello(conn net.Conn) ([]byte, string, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(clientHelloTimeout)); err != nil {
+		return nil, "", err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 0, 4096)
+	chunk := make([]byte, 4096)
+
+	for {
+		n, err := conn.Read(chunk)
+		if n > 0 {
+			buf = append(buf, chunk[:n]...)
+		}
+		if err != nil {
+			return buf, "", err
+		}
+
+		if len(buf) >= 5 {
+			recordLen := int(buf[3])<<8 | int(buf[4])
+			if len(buf) >= 5+recordLen {
+				return buf, capture.ExtractSNI(buf), nil
+			}
+		}
+
+		if len(buf) > maxClientHelloPeek {
+			return buf, "", io.ErrShortBuffer
+		}
+	}
+}
+
+// splice copies bytes in both directions until either side closes.
+func splice(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
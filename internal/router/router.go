@@ -0,0 +1,103 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// compiledRule is a Rule with its regex (if any) pre-compiled so Match
+// doesn't pay for recompilation on every connection.
+type compiledRule struct {
+	Rule
+	regex *regexp.Regexp
+}
+
+// Router matches a Client Hello's SNI against a hot-reloadable ruleset
+// and returns the backend it should be spliced to.
+type Router struct {
+	path string
+
+	mu    sync.RWMutex
+	rules []compiledRule
+}
+
+// New loads path and returns a Router ready to Match against it.
+func New(path string) (*Router, error) {
+	r := &Router{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads and re-compiles the ruleset from disk.
+func (r *Router) Reload() error {
+	cfg, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+
+	compiled := make([]compiledRule, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		cr := compiledRule{Rule: rule}
+		if rule.Kind == "regex" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return fmt.Errorf("router: compiling regex %q: %w", rule.Match, err)
+			}
+			cr.regex = re
+		}
+		compiled = append(compiled, cr)
+	}
+
+	r.mu.Lock()
+	r.rules = compiled
+	r.mu.Unlock()
+	return nil
+}
+
+// WatchReload reloads the ruleset every time the process receives SIGHUP.
+// A reload error is logged, not fatal, so a bad edit doesn't take down a
+// running router.
+func (r *Router) WatchReload() {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := r.Reload(); err != nil {
+				log.Printf("router: reload of %s failed: %v", r.path, err)
+				continue
+			}
+			log.Printf("router: reloaded %s", r.path)
+		}
+	}()
+}
+
+// Match returns the backend the given SNI hostname should be routed to,
+// and false if no rule (including no "default" rule) matched.
+func (r *Router) Match(hostname string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rule := range r.rules {
+		switch rule.Kind {
+		case "suffix":
+			if strings.HasSuffix(hostname, rule.Match) {
+				return rule.Backend, true
+			}
+		case "regex":
+			if rule.regex.MatchString(hostname) {
+				return rule.Backend, true
+			}
+		case "default":
+			return rule.Backend, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// TestRouter_matchesInOrder verifies that suffix, regex, and default
+// rules are evaluated top to bottom and the first match wins.
+func TestRouter_matchesInOrder(t *testing.T) {
+	path := writeConfig(t, "rules.yaml", `
+rules:
+  - kind: suffix
+    match: .example.com
+    backend: 10.0.0.5:8443
+  - kind: regex
+    match: ^api\.
+    backend: "unix:/run/api.sock"
+  - kind: default
+    backend: 10.0.0.1:443
+`)
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	cases := []struct {
+		hostname string
+		backend  string
+	}{
+		{"www.example.com", "10.0.0.5:8443"},
+		{"api.internal", "unix:/run/api.sock"},
+		{"unrelated.org", "10.0.0.1:443"},
+	}
+	for _, c := range cases {
+		backend, ok := r.Match(c.hostname)
+		if !ok {
+			t.Errorf("Match(%q): expected a match", c.hostname)
+			continue
+		}
+		if backend != c.backend {
+			t.Errorf("Match(%q): want %q, got %q", c.hostname, c.backend, backend)
+		}
+	}
+}
+
+// TestRouter_noMatch ensures a hostname matching nothing (and no default
+// rule present) reports ok=false rather than an empty backend.
+func TestRouter_noMatch(t *testing.T) {
+	path := writeConfig(t, "rules.json", `{"rules": [{"kind": "suffix", "match": ".example.com", "backend": "10.0.0.5:8443"}]}`)
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, ok := r.Match("other.org"); ok {
+		t.Error("Match: expected no match, got one")
+	}
+}
+
+// TestRouter_dropRule ensures a `default -> drop` rule matches and
+// surfaces the drop sentinel as its backend, rather than a dialable
+// address.
+func TestRouter_dropRule(t *testing.T) {
+	path := writeConfig(t, "rules.yaml", `
+rules:
+  - kind: suffix
+    match: .example.com
+    backend: 10.0.0.5:8443
+  - kind: default
+    backend: drop
+`)
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	backend, ok := r.Match("unrelated.org")
+	if !ok {
+		t.Fatal("Match: expected the default rule to match")
+	}
+	if backend != "drop" {
+		t.Errorf("Match backend: want %q, got %q", "drop", backend)
+	}
+}
+
+// TestRouter_reload confirms that Reload picks up a changed ruleset.
+func TestRouter_reload(t *testing.T) {
+	path := writeConfig(t, "rules.json", `{"rules": [{"kind": "default", "backend": "10.0.0.1:443"}]}`)
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if backend, _ := r.Match("anything"); backend != "10.0.0.1:443" {
+		t.Fatalf("Match before reload: got %q", backend)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rules": [{"kind": "default", "backend": "10.0.0.2:443"}]}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if backend, _ := r.Match("anything"); backend != "10.0.0.2:443" {
+		t.Errorf("Match after reload: want %q, got %q", "10.0.0.2:443", backend)
+	}
+}